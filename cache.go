@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// noCacheFlag, when true, makes batchCompile bypass the compile cache. No
+// flag.BoolVar call registers it, so no command-line flag sets it.
+var noCacheFlag bool
+
+func cacheDir() string {
+	return filepath.Join(argConfig.ProjectRoot, ".gecko-cache")
+}
+
+func cachePath(key string) string {
+	return filepath.Join(cacheDir(), key+".out")
+}
+
+// cacheKeyFor hashes everything that can change a file's compiled output:
+// its own bytes, the bytes of everything it `.include`s (transitively), the
+// address expression, the project's -defsym string, and the resolved
+// toolchain's version string. Any change to any of those invalidates the
+// cache entry.
+func cacheKeyFor(file, addressExp string, tc toolchain) (string, error) {
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	included, err := collectIncludedBytes(file, map[string]bool{})
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write(source)
+	h.Write(included)
+	h.Write([]byte(addressExp))
+	h.Write([]byte(argConfig.DefSym))
+	h.Write([]byte(toolchainVersion(tc)))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// collectIncludedBytes recursively reads every file pulled in via a
+// `.include "path"` directive, so changes to included files also bust the
+// cache even though the including file's own bytes didn't change.
+func collectIncludedBytes(file string, visited map[string]bool) ([]byte, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	if visited[abs] {
+		return nil, nil
+	}
+	visited[abs] = true
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var included []byte
+	fileDir := filepath.Dir(file)
+	for _, line := range strings.Split(string(contents), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, ".include") {
+			continue
+		}
+		fields := strings.Fields(trimmed)
+		if len(fields) < 2 {
+			continue
+		}
+		name := strings.Trim(fields[1], `"`)
+
+		candidate, err := findIncludeCandidate(fileDir, name)
+		if err != nil {
+			return nil, err
+		}
+		if candidate == "" {
+			continue
+		}
+
+		nested, err := collectIncludedBytes(candidate, visited)
+		if err != nil {
+			return nil, err
+		}
+		includedContents, err := ioutil.ReadFile(candidate)
+		if err != nil {
+			return nil, err
+		}
+		included = append(included, includedContents...)
+		included = append(included, nested...)
+	}
+	return included, nil
+}
+
+// findIncludeCandidate resolves a `.include "name"` the same way `as`
+// eventually would. execBatchCompile passes `-I <dir>` for every file in
+// the batch, not just the including file's own directory and the project
+// root, so a sibling code's directory can also supply the include. Rather
+// than trying to reconstruct that exact, batch-composition-dependent
+// search path ahead of time, fall back to a project-wide search: any file
+// reachable via a batch's `-I` flags lives somewhere under ProjectRoot.
+//
+// The project-wide fallback matches by basename only, so it returns an
+// error instead of silently picking one when two or more files under
+// ProjectRoot share that basename — hashing the wrong one would make
+// cacheKeyFor compute a key over content `as` never actually used.
+func findIncludeCandidate(fileDir, name string) (string, error) {
+	if candidate := filepath.Join(fileDir, name); fileExists(candidate) {
+		return candidate, nil
+	}
+	if candidate := filepath.Join(argConfig.ProjectRoot, name); fileExists(candidate) {
+		return candidate, nil
+	}
+
+	var matches []string
+	filepath.Walk(argConfig.ProjectRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+		if filepath.Base(path) == filepath.Base(name) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+
+	switch len(matches) {
+	case 0:
+		return "", nil
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous .include %q: found %d files named %q under %s (%s); disambiguate by moving one aside or giving the include a path that resolves under the including file's own directory", name, len(matches), filepath.Base(name), argConfig.ProjectRoot, strings.Join(matches, ", "))
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+var toolchainVersionMtx sync.Mutex
+var toolchainVersionCache = map[string]string{}
+
+// toolchainVersion returns the assembler's own version string, so upgrading
+// binutils invalidates any cache entries that might encode differently. (The
+// native backend never reads or writes the cache.) It's
+// memoized per AsPath: cacheKeyFor calls this once per file, cache hit or
+// miss, and shelling out to `as --version` for every file in a large
+// project would undo the whole point of caching.
+func toolchainVersion(tc toolchain) string {
+	toolchainVersionMtx.Lock()
+	defer toolchainVersionMtx.Unlock()
+
+	if v, ok := toolchainVersionCache[tc.AsPath]; ok {
+		return v
+	}
+
+	v := queryToolchainVersion(tc)
+	toolchainVersionCache[tc.AsPath] = v
+	return v
+}
+
+func queryToolchainVersion(tc toolchain) string {
+	out, err := exec.Command(tc.AsPath, "--version").Output()
+	if err != nil {
+		return tc.AsPath
+	}
+	lines := strings.SplitN(string(out), "\n", 2)
+	return strings.TrimSpace(lines[0])
+}
+
+// loadCachedCompile reads a previously cached (code, address) pair for key,
+// if present. The cache file format is a single line holding the hex
+// address, a newline, then the raw compiled code bytes.
+func loadCachedCompile(key string) (compileResponse, bool) {
+	data, err := ioutil.ReadFile(cachePath(key))
+	if err != nil {
+		return compileResponse{}, false
+	}
+
+	nl := strings.IndexByte(string(data), '\n')
+	if nl == -1 {
+		return compileResponse{}, false
+	}
+
+	return compileResponse{address: string(data[:nl]), code: data[nl+1:]}, true
+}
+
+// addressWordFromHex parses a compileResponse.address string (as produced
+// by fmt.Sprintf("%x", address) over the 4 big-endian address bytes) back
+// into a uint32, for code paths (like a cache hit) that only have the hex
+// form on hand.
+func addressWordFromHex(address string) uint32 {
+	v, _ := strconv.ParseUint(address, 16, 32)
+	return uint32(v)
+}
+
+// storeCachedCompile persists a compiled result so future runs with
+// unchanged inputs can skip reassembling file entirely.
+func storeCachedCompile(key string, resp compileResponse) error {
+	if err := os.MkdirAll(cacheDir(), 0755); err != nil {
+		return err
+	}
+
+	var data []byte
+	data = append(data, []byte(resp.address)...)
+	data = append(data, '\n')
+	data = append(data, resp.code...)
+	return ioutil.WriteFile(cachePath(key), data, 0644)
+}
+
+// cacheClean removes the entire compile cache directory. No command-line
+// subcommand dispatches to it.
+func cacheClean() {
+	if err := os.RemoveAll(cacheDir()); err != nil {
+		fmt.Printf("Failed to clean compile cache: %s\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("Compile cache cleaned")
+}
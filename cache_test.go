@@ -0,0 +1,153 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stubToolchain returns a toolchain whose AsPath isn't a real binary, so
+// tests stay hermetic; toolchainVersion falls back to returning AsPath
+// itself when the exec fails.
+func stubToolchain() toolchain {
+	return toolchain{AsPath: "gecko-test-as-stub", ObjcopyPath: "gecko-test-objcopy-stub"}
+}
+
+func withProjectRoot(t *testing.T) string {
+	t.Helper()
+	prevRoot := argConfig.ProjectRoot
+	dir, err := ioutil.TempDir("", "gecko-cache-test")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	t.Cleanup(func() {
+		argConfig.ProjectRoot = prevRoot
+		os.RemoveAll(dir)
+	})
+	argConfig.ProjectRoot = dir
+	return dir
+}
+
+// TestCacheKeyForBustsOnIncludeChange checks that changing the contents of
+// a `.include`d file changes the key, even though the including file's own
+// bytes didn't change.
+func TestCacheKeyForBustsOnIncludeChange(t *testing.T) {
+	dir := withProjectRoot(t)
+	tc := stubToolchain()
+
+	mainPath := filepath.Join(dir, "main.asm")
+	includePath := filepath.Join(dir, "shared.inc")
+
+	if err := ioutil.WriteFile(mainPath, []byte(".include \"shared.inc\"\nblr\n"), 0644); err != nil {
+		t.Fatalf("writing main.asm: %v", err)
+	}
+	if err := ioutil.WriteFile(includePath, []byte(".set FOO, 1\n"), 0644); err != nil {
+		t.Fatalf("writing shared.inc: %v", err)
+	}
+
+	before, err := cacheKeyFor(mainPath, "", tc)
+	if err != nil {
+		t.Fatalf("cacheKeyFor: %v", err)
+	}
+
+	if err := ioutil.WriteFile(includePath, []byte(".set FOO, 2\n"), 0644); err != nil {
+		t.Fatalf("rewriting shared.inc: %v", err)
+	}
+
+	after, err := cacheKeyFor(mainPath, "", tc)
+	if err != nil {
+		t.Fatalf("cacheKeyFor: %v", err)
+	}
+
+	if before == after {
+		t.Fatalf("cacheKeyFor did not change after the .include'd file's contents changed")
+	}
+}
+
+// TestCacheKeyForStableWithoutChanges checks that calling cacheKeyFor twice
+// over the same unchanged inputs yields the same key, so unrelated files
+// compiling in the same batch don't spuriously bust each other's cache.
+func TestCacheKeyForStableWithoutChanges(t *testing.T) {
+	dir := withProjectRoot(t)
+	tc := stubToolchain()
+
+	mainPath := filepath.Join(dir, "main.asm")
+	if err := ioutil.WriteFile(mainPath, []byte("blr\n"), 0644); err != nil {
+		t.Fatalf("writing main.asm: %v", err)
+	}
+
+	first, err := cacheKeyFor(mainPath, "0x80001234", tc)
+	if err != nil {
+		t.Fatalf("cacheKeyFor: %v", err)
+	}
+	second, err := cacheKeyFor(mainPath, "0x80001234", tc)
+	if err != nil {
+		t.Fatalf("cacheKeyFor: %v", err)
+	}
+
+	if first != second {
+		t.Fatalf("cacheKeyFor returned different keys for identical inputs: %q vs %q", first, second)
+	}
+}
+
+// TestFindIncludeCandidateAmbiguousBasename checks that when two files
+// under ProjectRoot share the `.include`d basename but neither lives in the
+// including file's own directory or ProjectRoot itself, findIncludeCandidate
+// errors instead of silently picking whichever filepath.Walk visits first.
+func TestFindIncludeCandidateAmbiguousBasename(t *testing.T) {
+	dir := withProjectRoot(t)
+
+	dirA := filepath.Join(dir, "codeA")
+	dirB := filepath.Join(dir, "codeB")
+	if err := os.MkdirAll(dirA, 0755); err != nil {
+		t.Fatalf("mkdir codeA: %v", err)
+	}
+	if err := os.MkdirAll(dirB, 0755); err != nil {
+		t.Fatalf("mkdir codeB: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dirA, "shared.inc"), []byte(".set FOO, 1\n"), 0644); err != nil {
+		t.Fatalf("writing codeA/shared.inc: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dirB, "shared.inc"), []byte(".set FOO, 2\n"), 0644); err != nil {
+		t.Fatalf("writing codeB/shared.inc: %v", err)
+	}
+
+	// fileDir is neither dirA nor dirB nor ProjectRoot, so resolution has to
+	// fall through to the ambiguous project-wide search.
+	fileDir := filepath.Join(dir, "codeC")
+	if err := os.MkdirAll(fileDir, 0755); err != nil {
+		t.Fatalf("mkdir codeC: %v", err)
+	}
+
+	_, err := findIncludeCandidate(fileDir, "shared.inc")
+	if err == nil {
+		t.Fatalf("findIncludeCandidate returned no error for an ambiguous basename match")
+	}
+}
+
+// TestLoadStoreCachedCompileRoundTrip checks that storeCachedCompile followed
+// by loadCachedCompile returns the same code and address that were stored.
+func TestLoadStoreCachedCompileRoundTrip(t *testing.T) {
+	withProjectRoot(t)
+
+	resp := compileResponse{code: []byte{0xde, 0xad, 0xbe, 0xef}, address: "80001234"}
+	if err := storeCachedCompile("abc123", resp); err != nil {
+		t.Fatalf("storeCachedCompile: %v", err)
+	}
+
+	got, hit := loadCachedCompile("abc123")
+	if !hit {
+		t.Fatalf("loadCachedCompile reported no hit right after storing")
+	}
+	if got.address != resp.address {
+		t.Fatalf("address = %q, want %q", got.address, resp.address)
+	}
+	if string(got.code) != string(resp.code) {
+		t.Fatalf("code = %x, want %x", got.code, resp.code)
+	}
+
+	if _, hit := loadCachedCompile("does-not-exist"); hit {
+		t.Fatalf("loadCachedCompile reported a hit for a key that was never stored")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// headerCode is one compiled code payload ready to be rendered as a C
+// header entry.
+type headerCode struct {
+	Name    string
+	Address uint32
+	Code    []byte
+}
+
+var headerMtx sync.Mutex
+var headerCodes []headerCode
+
+// wantsOutput reports whether the project config asked for the given
+// output kind ("gecko", "cheader", "raw") via its `outputs:` list. An empty
+// list preserves the historical behavior of emitting Gecko code text only.
+//
+// "gct" was floated in the original request alongside these three but isn't
+// implemented: producing a correct Gecko Code Type container needs the
+// existing Gecko-text codetype framing this snapshot doesn't have visibility
+// into, and a kind that's selectable but silently writes nothing is worse
+// than one that isn't offered yet. Add it back here (and to
+// recordConfiguredOutputs) once a recordGCTOutput exists to back it.
+func wantsOutput(kind string) bool {
+	if len(argConfig.Outputs) == 0 {
+		return kind == "gecko"
+	}
+	for _, o := range argConfig.Outputs {
+		if o == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// recordConfiguredOutputs is called after a code's bytes and injection
+// address are resolved (from either the subprocess or native backend), and
+// writes out whichever of the non-default `outputs:` kinds the config
+// asked for. "gecko" (the original Gecko code text output) stays the
+// implicit default and is written by the existing output path elsewhere;
+// this only covers the additional kinds introduced alongside `outputs:`.
+func recordConfiguredOutputs(file string, code []byte, address uint32) error {
+	if err := recordCHeaderOutput(file, code, address); err != nil {
+		return err
+	}
+	return recordRawOutput(file, code)
+}
+
+// recordCHeaderOutput writes a code out as a C header when "cheader" is one
+// of the configured `outputs:`.
+func recordCHeaderOutput(file string, code []byte, address uint32) error {
+	if !wantsOutput("cheader") {
+		return nil
+	}
+
+	hc := headerCode{Name: cHeaderIdentifier(file), Address: address, Code: code}
+
+	if argConfig.AggregateCHeader {
+		headerMtx.Lock()
+		defer headerMtx.Unlock()
+		headerCodes = append(headerCodes, hc)
+		return writeCHeaderFile(filepath.Join(argConfig.ProjectRoot, "gecko_codes.h"), headerCodes)
+	}
+
+	path := filepath.Join(filepath.Dir(file), hc.Name+".h")
+	return writeCHeaderFile(path, []headerCode{hc})
+}
+
+// recordRawOutput writes a code's compiled bytes out as a flat binary file
+// when "raw" is one of the configured `outputs:`.
+func recordRawOutput(file string, code []byte) error {
+	if !wantsOutput("raw") {
+		return nil
+	}
+
+	fileExt := filepath.Ext(file)
+	path := file[0:len(file)-len(fileExt)] + ".raw"
+	return ioutil.WriteFile(path, code, 0644)
+}
+
+// cHeaderIdentifier turns a code's source file path into a valid C
+// identifier fragment, e.g. "codes/infinite-ammo.asm" -> "infinite_ammo".
+func cHeaderIdentifier(file string) string {
+	base := filepath.Base(file)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, base)
+}
+
+// writeCHeaderFile renders codes as a self-contained C header, following
+// the data2c convention of a byte array plus address/length constants per
+// code.
+func writeCHeaderFile(path string, codes []headerCode) error {
+	guard := strings.ToUpper(cHeaderIdentifier(path)) + "_H"
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#ifndef %s\n#define %s\n\n", guard, guard)
+	fmt.Fprintf(&b, "#include <stdint.h>\n#include <stddef.h>\n\n")
+
+	for _, hc := range codes {
+		fmt.Fprintf(&b, "static const uint8_t %s_code[] = {", hc.Name)
+		for i, by := range hc.Code {
+			if i%12 == 0 {
+				b.WriteString("\n\t")
+			}
+			fmt.Fprintf(&b, "0x%02x, ", by)
+		}
+		b.WriteString("\n};\n")
+		fmt.Fprintf(&b, "static const uint32_t %s_addr = 0x%08x;\n", hc.Name, hc.Address)
+		fmt.Fprintf(&b, "static const size_t %s_len = %d;\n\n", hc.Name, len(hc.Code))
+	}
+
+	b.WriteString("#endif\n")
+
+	return ioutil.WriteFile(path, []byte(b.String()), 0644)
+}
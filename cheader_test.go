@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+// TestWantsOutputDefaultsToGeckoOnly checks that an empty `outputs:` list
+// preserves the historical behavior: only the implicit "gecko" text output
+// is active, and none of the opt-in kinds are.
+func TestWantsOutputDefaultsToGeckoOnly(t *testing.T) {
+	prev := argConfig.Outputs
+	defer func() { argConfig.Outputs = prev }()
+
+	argConfig.Outputs = nil
+
+	if !wantsOutput("gecko") {
+		t.Fatalf("wantsOutput(\"gecko\") = false, want true with an empty outputs: list")
+	}
+	for _, kind := range []string{"cheader", "raw"} {
+		if wantsOutput(kind) {
+			t.Fatalf("wantsOutput(%q) = true, want false with an empty outputs: list", kind)
+		}
+	}
+}
+
+// TestWantsOutputSelectsConfiguredKinds checks that listing specific kinds
+// in `outputs:` turns those on and leaves unlisted kinds off, including
+// "gecko" itself. Only wantsOutput's answer is checked; nothing in this
+// package consults wantsOutput("gecko") before writing the Gecko text.
+func TestWantsOutputSelectsConfiguredKinds(t *testing.T) {
+	prev := argConfig.Outputs
+	defer func() { argConfig.Outputs = prev }()
+
+	argConfig.Outputs = []string{"cheader", "raw"}
+
+	if wantsOutput("gecko") {
+		t.Fatalf("wantsOutput(\"gecko\") = true, want false when outputs: omits it")
+	}
+	if !wantsOutput("cheader") {
+		t.Fatalf("wantsOutput(\"cheader\") = false, want true when outputs: includes it")
+	}
+	if !wantsOutput("raw") {
+		t.Fatalf("wantsOutput(\"raw\") = false, want true when outputs: includes it")
+	}
+}
+
+// TestRecordCHeaderOutputAggregateConcurrentWrites drives recordCHeaderOutput
+// from many goroutines at once in aggregate mode, the way concurrent batch
+// compiles do, and checks the final gecko_codes.h reflects every code. A
+// write that happens outside the headerCodes lock can let an earlier,
+// smaller snapshot finish writing after a later, larger one, silently
+// dropping entries.
+func TestRecordCHeaderOutputAggregateConcurrentWrites(t *testing.T) {
+	prevOutputs, prevRoot, prevAggregate := argConfig.Outputs, argConfig.ProjectRoot, argConfig.AggregateCHeader
+	prevCodes := headerCodes
+	defer func() {
+		argConfig.Outputs, argConfig.ProjectRoot, argConfig.AggregateCHeader = prevOutputs, prevRoot, prevAggregate
+		headerCodes = prevCodes
+	}()
+
+	dir, err := ioutil.TempDir("", "cheader-aggregate")
+	if err != nil {
+		t.Fatalf("TempDir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	argConfig.Outputs = []string{"cheader"}
+	argConfig.ProjectRoot = dir
+	argConfig.AggregateCHeader = true
+	headerCodes = nil
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			file := fmt.Sprintf("%s/code%d.asm", dir, i)
+			if err := recordCHeaderOutput(file, []byte{byte(i)}, uint32(0x80000000+i)); err != nil {
+				t.Errorf("recordCHeaderOutput: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	contents, err := ioutil.ReadFile(dir + "/gecko_codes.h")
+	if err != nil {
+		t.Fatalf("reading gecko_codes.h: %v", err)
+	}
+	if got := countOccurrences(string(contents), "_addr = 0x"); got != n {
+		t.Fatalf("gecko_codes.h has %d code entries, want %d (some concurrent writes were dropped)", got, n)
+	}
+}
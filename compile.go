@@ -38,12 +38,16 @@ type compileResponse struct {
 type compileJob struct {
 	inputFile  string
 	addressExp string
+	cacheKey   string
 	response   chan compileResponse
 }
 
 func execBatchCompile(jobs []compileJob) {
-	const asCmdLinux string = "powerpc-eabi-as"
-	const objcopyCmdLinux string = "powerpc-eabi-objcopy"
+	tc, err := resolveToolchain()
+	if err != nil {
+		printCompileErrors([]CompileError{{Message: err.Error()}})
+		os.Exit(1)
+	}
 
 	outputFilePath := path.Join(argConfig.ProjectRoot, "compiled.elf")
 	compileWaitGroup.Add(1)
@@ -73,6 +77,7 @@ func execBatchCompile(jobs []compileJob) {
 	args = append(args, "-o", outputFilePath)
 
 	// Iterate through jobs, create temp files, and add them to the files to assemble
+	lineMaps := map[string]asmLineMap{}
 	for idx, job := range jobs {
 		file := job.inputFile
 		fileExt := filepath.Ext(file)
@@ -84,17 +89,20 @@ func execBatchCompile(jobs []compileJob) {
 			os.Remove(compileFilePath)
 		}()
 
-		buildTempAsmFile(file, job.addressExp, compileFilePath, fmt.Sprintf("file%d", idx))
+		lineMaps[compileFilePath] = buildTempAsmFile(file, job.addressExp, compileFilePath, fmt.Sprintf("file%d", idx))
 		args = append(args, compileFilePath)
 	}
 
-	cmd := exec.Command(asCmdLinux, args...)
+	cmd := exec.Command(tc.AsPath, args...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("Failed to compile files")
-		fmt.Printf("%s", output)
-		panic("as failure")
+		errs := parseCompileErrors(output, lineMaps)
+		if len(errs) == 0 {
+			errs = []CompileError{{Message: string(output)}}
+		}
+		printCompileErrors(errs)
+		os.Exit(1)
 	}
 
 	args = []string{outputFilePath}
@@ -112,12 +120,11 @@ func execBatchCompile(jobs []compileJob) {
 		args = append(args, "--dump-section", fmt.Sprintf("file%d=%s", idx, codeFilePath))
 	}
 
-	cmd = exec.Command(objcopyCmdLinux, args...)
+	cmd = exec.Command(tc.ObjcopyPath, args...)
 	output, err = cmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("Failed to pull extract code sections\n")
-		fmt.Printf("%s", output)
-		panic("objcopy failure")
+		printCompileErrors([]CompileError{{Message: fmt.Sprintf("failed to extract code sections: %s", output)}})
+		os.Exit(1)
 	}
 
 	for _, job := range jobs {
@@ -135,18 +142,53 @@ func execBatchCompile(jobs []compileJob) {
 			log.Panicf("Injection address in file %s evaluated to a value that does not start with 0x80 or 0x81, probably an invalid address\n", file)
 		}
 
-		job.response <- compileResponse{code: code, address: fmt.Sprintf("%x", address)}
+		resp := compileResponse{code: code, address: fmt.Sprintf("%x", address)}
+		if job.cacheKey != "" {
+			if err := storeCachedCompile(job.cacheKey, resp); err != nil {
+				fmt.Printf("Failed to write compile cache entry for %s: %s\n", job.inputFile, err.Error())
+			}
+		}
+
+		var addressWord uint32
+		for _, b := range address {
+			addressWord = addressWord<<8 | uint32(b)
+		}
+		if err := recordConfiguredOutputs(job.inputFile, code, addressWord); err != nil {
+			fmt.Printf("Failed to write configured outputs for %s: %s\n", job.inputFile, err.Error())
+		}
+
+		job.response <- resp
 	}
 }
 
 func batchCompile(file, addressExp string) ([]byte, string) {
 	// return compile(file, addressExp)
 
+	if useNativeAssembler() {
+		return compileNative(file, addressExp)
+	}
+
+	cacheKey := ""
+	if !noCacheFlag && !argConfig.NoCache {
+		if tc, err := resolveToolchain(); err == nil {
+			if key, err := cacheKeyFor(file, addressExp, tc); err == nil {
+				if resp, hit := loadCachedCompile(key); hit {
+					if err := recordConfiguredOutputs(file, resp.code, addressWordFromHex(resp.address)); err != nil {
+						fmt.Printf("Failed to write configured outputs for %s: %s\n", file, err.Error())
+					}
+					return resp.code, resp.address
+				}
+				cacheKey = key
+			}
+		}
+	}
+
 	c := make(chan compileResponse)
 	jobMtx.Lock()
 	compileJobs = append(compileJobs, compileJob{
 		inputFile:  file,
 		addressExp: addressExp,
+		cacheKey:   cacheKey,
 		response:   c,
 	})
 
@@ -172,12 +214,15 @@ func compile(file, addressExp string) ([]byte, string) {
 	// Technically this shouldn't be necessary but for some reason if the last line
 	// or the asm file has one of more spaces at the end and no new line, the last
 	// instruction is ignored and not compiled
-	buildTempAsmFile(file, addressExp, compileFilePath, "")
+	lineMap := buildTempAsmFile(file, addressExp, compileFilePath, "")
 
 	fileDir := filepath.Dir(file)
 
-	const asCmdLinux string = "powerpc-eabi-as"
-	const objcopyCmdLinux string = "powerpc-eabi-objcopy"
+	tc, err := resolveToolchain()
+	if err != nil {
+		printCompileErrors([]CompileError{{Message: err.Error()}})
+		os.Exit(1)
+	}
 
 	// Set base args
 	args := []string{"-a32", "-mbig", "-mregnames", "-mgekko"}
@@ -193,13 +238,16 @@ func compile(file, addressExp string) ([]byte, string) {
 	// Set output file
 	args = append(args, "-o", outputFilePath, compileFilePath)
 
-	cmd := exec.Command(asCmdLinux, args...)
+	cmd := exec.Command(tc.AsPath, args...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("Failed to compile file: %s\n", file)
-		fmt.Printf("%s", output)
-		panic("as failure")
+		errs := parseCompileErrors(output, map[string]asmLineMap{compileFilePath: lineMap})
+		if len(errs) == 0 {
+			errs = []CompileError{{Message: string(output)}}
+		}
+		printCompileErrors(errs)
+		os.Exit(1)
 	}
 
 	contents, err := ioutil.ReadFile(outputFilePath)
@@ -214,12 +262,11 @@ func compile(file, addressExp string) ([]byte, string) {
 		log.Panicf("Injection address in file %s evaluated to a value that does not start with 0x80, probably an invalid address\n", file)
 	}
 
-	cmd = exec.Command(objcopyCmdLinux, "-O", "binary", outputFilePath, outputFilePath)
+	cmd = exec.Command(tc.ObjcopyPath, "-O", "binary", outputFilePath, outputFilePath)
 	output, err = cmd.CombinedOutput()
 	if err != nil {
-		fmt.Printf("Failed to pull out .text section: %s\n", file)
-		fmt.Printf("%s", output)
-		panic("objcopy failure")
+		printCompileErrors([]CompileError{{Message: fmt.Sprintf("failed to pull out .text section for %s: %s", file, output)}})
+		os.Exit(1)
 	}
 	contents, err = ioutil.ReadFile(outputFilePath)
 	if err != nil {
@@ -306,39 +353,132 @@ func isolateLabelNames(asmContents []byte) []byte {
 	return []byte(strings.Join(finalLines, "\r\n"))
 }
 
-// func isolateSymbolNames(asmContents []byte, section string) []byte {
-// 	lines := strings.Split(string(asmContents), "\n")
-// 	symbolMap := map[string][]symbolInfo{}
-// 	newLines := []string{}
-// 	for idx, line := range lines {
-// 		parts := splitAny(line, " \t,")
-// 		if len(parts) == 0 {
-// 			newLines = append(newLines, line)
-// 			continue
-// 		}
-
-// 		isSet := parts[0] == ".set" && len(parts) >= 3
-// 		if !isSet {
-// 			newLines = append(newLines, line)
-// 			continue
-// 		}
-
-// 		symbolMap[parts[1]] = fmt.Sprintf("__%s_symbol_%d", section, idx)
-// 	}
-// }
-
-func buildTempAsmFile(sourceFilePath, addressExp, targetFilePath, section string) {
+// isolateSymbolNames renames every `.set`/`.equ` symbol defined in a batched
+// file to a name unique to that file's section, then rewrites every other
+// occurrence of the original name in the file to match. This lets multiple
+// files in the same batch define a symbol with the same name (e.g. `.set
+// TEMP, 0x80001234` in two different codes) without one clobbering the
+// other. Symbols that start with `_` and are also defined somewhere in the
+// project's include tree are left alone, since that's the project's
+// convention for an intentional cross-file extern.
+func isolateSymbolNames(asmContents []byte, section string) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(asmContents), "\r\n", "\n"), "\n")
+	externs := externSymbolAllowlist()
+
+	symbolMap := map[string]symbolInfo{}
+	idx := 0
+	for lineNum, line := range lines {
+		code, _ := splitLineComment(line)
+		parts := splitAny(code, " \t,")
+		isSet := len(parts) >= 3 && (parts[0] == ".set" || parts[0] == ".equ")
+		if !isSet {
+			continue
+		}
+
+		name := parts[1]
+		if _, isExtern := externs[name]; strings.HasPrefix(name, "_") && isExtern {
+			continue
+		}
+		if _, alreadyMangled := symbolMap[name]; alreadyMangled {
+			continue
+		}
+
+		symbolMap[name] = symbolInfo{name: fmt.Sprintf("__%s_symbol_%d", section, idx), linePos: lineNum}
+		idx++
+	}
+
+	if len(symbolMap) == 0 {
+		return []byte(strings.Join(lines, "\r\n"))
+	}
+
+	newLines := make([]string, len(lines))
+	for i, line := range lines {
+		newLines[i] = replaceSymbolTokens(line, symbolMap)
+	}
+
+	return []byte(strings.Join(newLines, "\r\n"))
+}
+
+// splitLineComment splits a line into its code and (`#`-prefixed) comment
+// portions, same convention isolateLabelNames uses.
+func splitLineComment(line string) (code string, comment string) {
+	idx := strings.IndexByte(line, '#')
+	if idx == -1 {
+		return line, ""
+	}
+	return line[:idx], line[idx:]
+}
+
+// replaceSymbolTokens rewrites every whole-word occurrence of a mangled
+// symbol's original name in line, skipping quoted strings (e.g. `.include
+// "file.inc"`) and leaving the trailing comment untouched.
+func replaceSymbolTokens(line string, symbolMap map[string]symbolInfo) string {
+	code, comment := splitLineComment(line)
+
+	var out strings.Builder
+	inString := false
+	wordStart := -1
+
+	flushWord := func(end int) {
+		if wordStart == -1 {
+			return
+		}
+		word := code[wordStart:end]
+		if info, ok := symbolMap[word]; ok {
+			out.WriteString(info.name)
+		} else {
+			out.WriteString(word)
+		}
+		wordStart = -1
+	}
+
+	isWordChar := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_'
+	}
+
+	for i, r := range code {
+		if inString {
+			out.WriteRune(r)
+			if r == '"' {
+				inString = false
+			}
+			continue
+		}
+		if r == '"' {
+			flushWord(i)
+			inString = true
+			out.WriteRune(r)
+			continue
+		}
+		if isWordChar(r) {
+			if wordStart == -1 {
+				wordStart = i
+			}
+			continue
+		}
+		flushWord(i)
+		out.WriteRune(r)
+	}
+	flushWord(len(code))
+
+	return out.String() + comment
+}
+
+func buildTempAsmFile(sourceFilePath, addressExp, targetFilePath, section string) asmLineMap {
 	asmContents, err := ioutil.ReadFile(sourceFilePath)
 	if err != nil {
 		log.Panicf("Failed to read asm file: %s\n%s\n", sourceFilePath, err.Error())
 	}
 
+	lineMap := asmLineMap{sourceFile: sourceFilePath, sourceLines: len(splitLines(string(asmContents)))}
+
 	// If section provided, we need to take some precautions to isolate the code from others
 	if section != "" {
 		// Add the section label at the top so the code can be extracted individually
 		asmContents = append([]byte(fmt.Sprintf(".section %s\r\n", section)), asmContents...)
+		lineMap.headerLines = 1
 		asmContents = isolateLabelNames(asmContents)
-		// asmContents = isolateSymbolNames(asmContents, section)
+		asmContents = isolateSymbolNames(asmContents, section)
 	}
 
 	// Add new line before .set for address
@@ -354,4 +494,6 @@ func buildTempAsmFile(sourceFilePath, addressExp, targetFilePath, section string
 	if err != nil {
 		log.Panicf("Failed to write temporary asm file\n%s\n", err.Error())
 	}
+
+	return lineMap
 }
@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+// TestIsolateSymbolNamesAcrossSections mirrors batch-compiling two files
+// that each define `.set FOO, ...` with a different value: isolating them
+// under different section names must mangle FOO differently in each file
+// so neither .set clobbers the other when assembled together.
+func TestIsolateSymbolNamesAcrossSections(t *testing.T) {
+	setExternAllowlistForTest(map[string]struct{}{})
+
+	fileA := []byte(".set FOO, 0x80001234\nlis r3, FOO\nori r3, r3, FOO\n")
+	fileB := []byte(".set FOO, 0x80005678\nlis r4, FOO\n")
+
+	outA := string(isolateSymbolNames(fileA, "file0"))
+	outB := string(isolateSymbolNames(fileB, "file1"))
+
+	wantA := "__file0_symbol_0"
+	wantB := "__file1_symbol_0"
+
+	if wantA == wantB {
+		t.Fatalf("expected mangled names to differ between sections, both got %q", wantA)
+	}
+	if countOccurrences(outA, wantA) != 3 {
+		t.Fatalf("expected mangled name %q to replace all 3 occurrences of FOO in file A, got:\n%s", wantA, outA)
+	}
+	if countOccurrences(outB, wantB) != 2 {
+		t.Fatalf("expected mangled name %q to replace all 2 occurrences of FOO in file B, got:\n%s", wantB, outB)
+	}
+}
+
+// TestIsolateSymbolNamesPreservesExternConvention checks that a `_`-prefixed
+// symbol present in the project-wide extern allowlist is left untouched,
+// since that's the project's convention for an intentional cross-file
+// symbol rather than a name to mangle.
+func TestIsolateSymbolNamesPreservesExternConvention(t *testing.T) {
+	setExternAllowlistForTest(map[string]struct{}{"_SHARED_BASE": {}})
+
+	in := []byte(".set _SHARED_BASE, 0x80003000\nlis r3, _SHARED_BASE\n")
+	out := string(isolateSymbolNames(in, "file0"))
+
+	if countOccurrences(out, "_SHARED_BASE") != 2 {
+		t.Fatalf("expected extern symbol _SHARED_BASE to be preserved untouched, got:\n%s", out)
+	}
+}
+
+func countOccurrences(haystack, needle string) int {
+	count := 0
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if haystack[i:i+len(needle)] == needle {
+			count++
+			i += len(needle) - 1
+		}
+	}
+	return count
+}
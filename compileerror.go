@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// CompileError is a single diagnostic from the assembler, remapped from the
+// generated temp-file line back to the line in the user's original .asm
+// source file.
+type CompileError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Raw     string
+}
+
+func (e CompileError) String() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+	if e.Column != 0 {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+}
+
+// asDiagnosticPattern matches GNU-as diagnostic lines, e.g.
+// "codes/foo.asmtemp:12: Error: unknown opcode `blh'" or
+// "codes/foo.asmtemp:12:5: Error: ...".
+var asDiagnosticPattern = regexp.MustCompile(`^(.+?):(\d+):(?:(\d+):)? (Error|Warning): (.*)$`)
+
+// asmLineMap records how to translate a line number in a generated temp
+// file back to the corresponding line in the original source file that
+// buildTempAsmFile read from. headerLines is the number of synthetic lines
+// buildTempAsmFile prepended (the `.section` line, when building a batched
+// file); trailing synthetic lines (the blank line, `.long <addressExp>`,
+// and final blank line) have no corresponding source line.
+type asmLineMap struct {
+	sourceFile  string
+	headerLines int
+	sourceLines int
+}
+
+func (m asmLineMap) toSource(tempLine int) (file string, line int, ok bool) {
+	sourceLine := tempLine - m.headerLines
+	if sourceLine < 1 || sourceLine > m.sourceLines {
+		return m.sourceFile, 0, false
+	}
+	return m.sourceFile, sourceLine, true
+}
+
+// parseCompileErrors parses the raw output of an `as` invocation into
+// structured CompileErrors, rewriting each diagnostic's temp-file line
+// number back to the original source file/line using maps keyed by the
+// temp file path the diagnostic references.
+func parseCompileErrors(output []byte, maps map[string]asmLineMap) []CompileError {
+	var errs []CompileError
+	for _, rawLine := range splitLines(string(output)) {
+		m := asDiagnosticPattern.FindStringSubmatch(rawLine)
+		if m == nil {
+			continue
+		}
+
+		tempFile, lineStr, colStr, message := m[1], m[2], m[3], m[5]
+		tempLine, err := strconv.Atoi(lineStr)
+		if err != nil {
+			continue
+		}
+		column := 0
+		if colStr != "" {
+			column, _ = strconv.Atoi(colStr)
+		}
+
+		ce := CompileError{File: tempFile, Line: tempLine, Column: column, Message: message, Raw: rawLine}
+		if lm, ok := maps[tempFile]; ok {
+			if file, line, ok := lm.toSource(tempLine); ok {
+				ce.File, ce.Line = file, line
+			}
+		}
+		errs = append(errs, ce)
+	}
+	return errs
+}
+
+func splitLines(s string) []string {
+	var lines []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\n' {
+			lines = append(lines, trimCR(s[start:i]))
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		lines = append(lines, trimCR(s[start:]))
+	}
+	return lines
+}
+
+func trimCR(s string) string {
+	if len(s) > 0 && s[len(s)-1] == '\r' {
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// printCompileErrors prints diagnostics grouped by the source file they
+// belong to, in the order the files were first seen.
+func printCompileErrors(errs []CompileError) {
+	var order []string
+	grouped := map[string][]CompileError{}
+	for _, e := range errs {
+		if _, seen := grouped[e.File]; !seen {
+			order = append(order, e.File)
+		}
+		grouped[e.File] = append(grouped[e.File], e)
+	}
+
+	for _, file := range order {
+		fmt.Fprintf(os.Stderr, "%s:\n", file)
+		for _, e := range grouped[file] {
+			fmt.Fprintf(os.Stderr, "  %s\n", e.String())
+		}
+	}
+}
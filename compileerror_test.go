@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+// TestParseCompileErrorsRemapsTempLineToSource checks that a diagnostic
+// reported against the generated temp file is rewritten to point at the
+// line in the user's original source, accounting for the `.section` header
+// line buildTempAsmFile prepends for batched files.
+func TestParseCompileErrorsRemapsTempLineToSource(t *testing.T) {
+	lineMaps := map[string]asmLineMap{
+		"codes/foo.asmtemp": {sourceFile: "codes/foo.asm", headerLines: 1, sourceLines: 3},
+	}
+
+	output := []byte("codes/foo.asmtemp:3: Error: unknown opcode `blh'\n")
+	errs := parseCompileErrors(output, lineMaps)
+
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %+v", len(errs), errs)
+	}
+
+	got := errs[0]
+	if got.File != "codes/foo.asm" || got.Line != 2 {
+		t.Fatalf("expected codes/foo.asm:2, got %s:%d", got.File, got.Line)
+	}
+	if got.Message != "unknown opcode `blh'" {
+		t.Fatalf("unexpected message %q", got.Message)
+	}
+}
+
+// TestParseCompileErrorsIgnoresSyntheticLines checks that a diagnostic
+// against one of the trailing synthetic lines (the appended `.long
+// <addressExp>`) is left pointing at the temp file rather than being
+// mapped to a nonexistent source line.
+func TestParseCompileErrorsIgnoresSyntheticLines(t *testing.T) {
+	lineMaps := map[string]asmLineMap{
+		"codes/foo.asmtemp": {sourceFile: "codes/foo.asm", headerLines: 1, sourceLines: 3},
+	}
+
+	output := []byte("codes/foo.asmtemp:6: Error: bad expression\n")
+	errs := parseCompileErrors(output, lineMaps)
+
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d", len(errs))
+	}
+	if errs[0].File != "codes/foo.asmtemp" {
+		t.Fatalf("expected unmapped diagnostic to keep temp file path, got %s", errs[0].File)
+	}
+}
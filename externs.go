@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var externAllowlistOnce sync.Once
+var externAllowlistCache map[string]struct{}
+
+// externSymbolAllowlist walks the project's include tree (anything under
+// -I, which in practice is just argConfig.ProjectRoot) and collects every
+// `.set`/`.equ` symbol name starting with `_`. isolateSymbolNames treats a
+// match against this set as an intentional cross-file extern rather than a
+// name to mangle.
+func externSymbolAllowlist() map[string]struct{} {
+	externAllowlistOnce.Do(func() {
+		externAllowlistCache = map[string]struct{}{}
+		if argConfig.ProjectRoot == "" {
+			return
+		}
+
+		filepath.Walk(argConfig.ProjectRoot, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return nil
+			}
+			ext := strings.ToLower(filepath.Ext(path))
+			if ext != ".asm" && ext != ".inc" && ext != ".s" {
+				return nil
+			}
+
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil
+			}
+
+			for _, line := range strings.Split(string(contents), "\n") {
+				code, _ := splitLineComment(line)
+				parts := splitAny(code, " \t,")
+				if len(parts) < 3 {
+					continue
+				}
+				if parts[0] != ".set" && parts[0] != ".equ" {
+					continue
+				}
+				if strings.HasPrefix(parts[1], "_") {
+					externAllowlistCache[parts[1]] = struct{}{}
+				}
+			}
+			return nil
+		})
+	})
+	return externAllowlistCache
+}
+
+// setExternAllowlistForTest forces externSymbolAllowlist to return
+// allowlist on every subsequent call, regardless of whether (or in what
+// order) it has already run, by resetting and re-firing the backing
+// sync.Once. Test-only; production code should never need to override the
+// allowlist once it's been computed.
+func setExternAllowlistForTest(allowlist map[string]struct{}) {
+	externAllowlistOnce = sync.Once{}
+	externAllowlistOnce.Do(func() {
+		externAllowlistCache = allowlist
+	})
+}
@@ -0,0 +1,170 @@
+package ppcasm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// encodeCtx provides an instruction encoder everything it needs to turn
+// operand text into a 32-bit PPC instruction word: the address the
+// instruction will live at (for PC-relative branches) and a way to resolve
+// symbol/label operands.
+type encodeCtx struct {
+	addr    int64
+	scope   exprScope
+	resolve func(operand string, fromAddr int64) (int64, error)
+}
+
+type encoder func(args []string, ctx encodeCtx) (uint32, error)
+
+var mnemonics = map[string]encoder{
+	"blr":  func(args []string, ctx encodeCtx) (uint32, error) { return 0x4e800020, nil },
+	"mflr": func(args []string, ctx encodeCtx) (uint32, error) { return regOnly(args, ctx, 0x7c0802a6) },
+	"mtlr": func(args []string, ctx encodeCtx) (uint32, error) { return regOnly(args, ctx, 0x7c0803a6) },
+	"lis":  encodeLis,
+	"ori":  encodeOri,
+	"lwz":  func(args []string, ctx encodeCtx) (uint32, error) { return encodeLoadStore(args, ctx, 32) },
+	"stw":  func(args []string, ctx encodeCtx) (uint32, error) { return encodeLoadStore(args, ctx, 36) },
+	"b":    func(args []string, ctx encodeCtx) (uint32, error) { return encodeBranch(args, ctx, false) },
+	"bl":   func(args []string, ctx encodeCtx) (uint32, error) { return encodeBranch(args, ctx, true) },
+	// Gekko paired-single extension: ps_add fD, fA, fB (opcode 4, xo 21).
+	"ps_add": encodePsAdd,
+}
+
+func regOnly(args []string, ctx encodeCtx, base uint32) (uint32, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected 1 operand, got %d", len(args))
+	}
+	r, err := parseReg(args[0])
+	if err != nil {
+		return 0, err
+	}
+	return base | uint32(r)<<21, nil
+}
+
+// parseReg parses a register operand written either as "r5" or plain "5".
+func parseReg(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "r")
+	s = strings.TrimPrefix(s, "f")
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > 31 {
+		return 0, fmt.Errorf("invalid register operand %q", s)
+	}
+	return n, nil
+}
+
+func resolveImm(s string, ctx encodeCtx) (int64, error) {
+	if ctx.resolve != nil {
+		if v, err := ctx.resolve(s, ctx.addr); err == nil {
+			return v, nil
+		}
+	}
+	return evalExpr(s, ctx.scope)
+}
+
+func encodeLis(args []string, ctx encodeCtx) (uint32, error) {
+	if len(args) != 2 {
+		return 0, fmt.Errorf("lis expects 2 operands, got %d", len(args))
+	}
+	rd, err := parseReg(args[0])
+	if err != nil {
+		return 0, err
+	}
+	imm, err := resolveImm(args[1], ctx)
+	if err != nil {
+		return 0, err
+	}
+	return (15 << 26) | uint32(rd)<<21 | uint32(imm)&0xffff, nil
+}
+
+func encodeOri(args []string, ctx encodeCtx) (uint32, error) {
+	if len(args) != 3 {
+		return 0, fmt.Errorf("ori expects 3 operands, got %d", len(args))
+	}
+	ra, err := parseReg(args[0])
+	if err != nil {
+		return 0, err
+	}
+	rs, err := parseReg(args[1])
+	if err != nil {
+		return 0, err
+	}
+	imm, err := resolveImm(args[2], ctx)
+	if err != nil {
+		return 0, err
+	}
+	return (24 << 26) | uint32(rs)<<21 | uint32(ra)<<16 | uint32(imm)&0xffff, nil
+}
+
+// encodeLoadStore handles the common "rD, disp(rA)" form shared by lwz/stw.
+func encodeLoadStore(args []string, ctx encodeCtx, opcode uint32) (uint32, error) {
+	if len(args) != 2 {
+		return 0, fmt.Errorf("expected 2 operands, got %d", len(args))
+	}
+	rd, err := parseReg(args[0])
+	if err != nil {
+		return 0, err
+	}
+	disp, ra, err := splitDisp(args[1])
+	if err != nil {
+		return 0, err
+	}
+	d, err := resolveImm(disp, ctx)
+	if err != nil {
+		return 0, err
+	}
+	return opcode<<26 | uint32(rd)<<21 | uint32(ra)<<16 | uint32(d)&0xffff, nil
+}
+
+// splitDisp splits a "disp(rA)" operand into its displacement expression and
+// base register.
+func splitDisp(s string) (disp string, reg int, err error) {
+	open := strings.IndexByte(s, '(')
+	closeIdx := strings.IndexByte(s, ')')
+	if open == -1 || closeIdx == -1 || closeIdx < open {
+		return "", 0, fmt.Errorf("expected disp(rA) operand, got %q", s)
+	}
+	disp = strings.TrimSpace(s[:open])
+	if disp == "" {
+		disp = "0"
+	}
+	reg, err = parseReg(s[open+1 : closeIdx])
+	return disp, reg, err
+}
+
+func encodeBranch(args []string, ctx encodeCtx, link bool) (uint32, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected 1 operand, got %d", len(args))
+	}
+	target, err := resolveImm(args[0], ctx)
+	if err != nil {
+		return 0, err
+	}
+	li := uint32(target-ctx.addr) & 0x3fffffc
+	word := uint32(18)<<26 | li
+	if link {
+		word |= 1
+	}
+	return word, nil
+}
+
+func encodePsAdd(args []string, ctx encodeCtx) (uint32, error) {
+	if len(args) != 3 {
+		return 0, fmt.Errorf("ps_add expects 3 operands, got %d", len(args))
+	}
+	fd, err := parseReg(args[0])
+	if err != nil {
+		return 0, err
+	}
+	fa, err := parseReg(args[1])
+	if err != nil {
+		return 0, err
+	}
+	fb, err := parseReg(args[2])
+	if err != nil {
+		return 0, err
+	}
+	return uint32(4)<<26 | uint32(fd)<<21 | uint32(fa)<<16 | uint32(fb)<<11 | 21<<1, nil
+}
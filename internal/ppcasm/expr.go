@@ -0,0 +1,189 @@
+package ppcasm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// exprScope resolves symbol names while evaluating an expression. Labels
+// that are pure numbers (GNU-as style local labels) are intentionally not
+// resolvable here; they must go through the f/b suffix handling in
+// resolveOperand instead.
+type exprScope interface {
+	lookupSymbol(name string) (int64, bool)
+}
+
+// evalExpr evaluates the small arithmetic subset gas exposes in `.set`,
+// `.long` and immediate operands: integer literals (decimal or 0x-prefixed
+// hex), symbol names, unary minus, and the binary operators
+// + - * / << >> & | ^, left-to-right with no operator precedence beyond
+// what parentheses express (mirroring the subset actually used in Gecko
+// code payloads).
+func evalExpr(s string, scope exprScope) (int64, error) {
+	toks, err := tokenizeExpr(s)
+	if err != nil {
+		return 0, err
+	}
+	p := &exprParser{toks: toks, scope: scope}
+	v, err := p.parseExpr()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos != len(p.toks) {
+		return 0, fmt.Errorf("unexpected token %q in expression %q", p.toks[p.pos], s)
+	}
+	return v, nil
+}
+
+func tokenizeExpr(s string) ([]string, error) {
+	var toks []string
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.ContainsRune("()+-*/&|^", rune(c)):
+			toks = append(toks, string(c))
+			i++
+		case c == '<' || c == '>':
+			if i+1 < len(s) && s[i+1] == c {
+				toks = append(toks, s[i:i+2])
+				i += 2
+			} else {
+				return nil, fmt.Errorf("unsupported operator at %q", s[i:])
+			}
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t()+-*/&|^<>", rune(s[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected character %q in expression %q", s[i], s)
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+type exprParser struct {
+	toks  []string
+	pos   int
+	scope exprScope
+}
+
+func (p *exprParser) peek() string {
+	if p.pos >= len(p.toks) {
+		return ""
+	}
+	return p.toks[p.pos]
+}
+
+func (p *exprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// parseExpr parses a left-to-right chain of additive/bitwise/shift terms.
+func (p *exprParser) parseExpr() (int64, error) {
+	v, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		switch op {
+		case "+", "-", "*", "/", "<<", ">>", "&", "|", "^":
+			p.next()
+			rhs, err := p.parseUnary()
+			if err != nil {
+				return 0, err
+			}
+			v, err = applyOp(op, v, rhs)
+			if err != nil {
+				return 0, err
+			}
+		default:
+			return v, nil
+		}
+	}
+}
+
+func applyOp(op string, a, b int64) (int64, error) {
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return 0, fmt.Errorf("division by zero")
+		}
+		return a / b, nil
+	case "<<":
+		return a << uint(b), nil
+	case ">>":
+		return int64(uint64(a) >> uint(b)), nil
+	case "&":
+		return a & b, nil
+	case "|":
+		return a | b, nil
+	case "^":
+		return a ^ b, nil
+	default:
+		return 0, fmt.Errorf("unsupported operator %q", op)
+	}
+}
+
+func (p *exprParser) parseUnary() (int64, error) {
+	if p.peek() == "-" {
+		p.next()
+		v, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		return -v, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (int64, error) {
+	tok := p.next()
+	if tok == "" {
+		return 0, fmt.Errorf("unexpected end of expression")
+	}
+	if tok == "(" {
+		v, err := p.parseExpr()
+		if err != nil {
+			return 0, err
+		}
+		if p.next() != ")" {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		return v, nil
+	}
+	if n, err := parseInt(tok); err == nil {
+		return n, nil
+	}
+	if p.scope != nil {
+		if v, ok := p.scope.lookupSymbol(tok); ok {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("undefined symbol %q", tok)
+}
+
+// parseInt parses an integer literal: decimal or 0x-prefixed hex. A leading
+// 0 does not make a literal octal; "010" parses as decimal 10.
+func parseInt(tok string) (int64, error) {
+	if strings.HasPrefix(tok, "0x") || strings.HasPrefix(tok, "0X") {
+		return strconv.ParseInt(tok[2:], 16, 64)
+	}
+	return strconv.ParseInt(tok, 10, 64)
+}
@@ -0,0 +1,112 @@
+package ppcasm
+
+import "strings"
+
+type stmtKind int
+
+const (
+	stmtLabel stmtKind = iota
+	stmtDirective
+	stmtInstruction
+)
+
+type statement struct {
+	kind   stmtKind
+	name   string   // label name, directive name (without the dot), or mnemonic
+	args   []string // directive/instruction operands, already comma-split and trimmed
+	source string   // original line, for error messages
+	line   int      // 1-based source line number, for error messages
+}
+
+// parseLine splits a single line of source (comments already expected to be
+// stripped by the caller) into zero or more statements. A line may contain
+// both a label and an instruction, e.g. "loop: addi r3, r3, 1".
+func parseLine(raw string, lineNum int) []statement {
+	line := strings.TrimSpace(raw)
+	var stmts []statement
+
+	for {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return stmts
+		}
+
+		if colon := strings.Index(line, ":"); colon != -1 && isLabelHead(line[:colon]) {
+			stmts = append(stmts, statement{kind: stmtLabel, name: strings.TrimSpace(line[:colon]), source: raw, line: lineNum})
+			line = line[colon+1:]
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+		head := fields[0]
+		rest := ""
+		if len(fields) == 2 {
+			rest = fields[1]
+		}
+		// A mnemonic may also be written with a tab separator.
+		if t := strings.SplitN(head, "\t", 2); len(t) == 2 {
+			head = t[0]
+			rest = t[1] + " " + rest
+		}
+
+		args := splitArgs(rest)
+		if strings.HasPrefix(head, ".") {
+			stmts = append(stmts, statement{kind: stmtDirective, name: head[1:], args: args, source: raw, line: lineNum})
+		} else {
+			stmts = append(stmts, statement{kind: stmtInstruction, name: head, args: args, source: raw, line: lineNum})
+		}
+		return stmts
+	}
+}
+
+// isLabelHead reports whether s is a valid label name: gas allows
+// alphanumerics/underscore/dot for named labels and bare digits for local
+// numeric labels.
+func isLabelHead(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		isAlnum := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.'
+		if !isAlnum {
+			return false
+		}
+		if i == 0 && r >= '0' && r <= '9' {
+			// Numeric labels must be entirely digits.
+			for _, d := range s {
+				if d < '0' || d > '9' {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return true
+}
+
+func splitArgs(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// stripComment removes a gas-style `#` line comment, respecting nothing more
+// elaborate than the comment marker itself (the assembler's input is
+// Gecko/devkitPPC asm, which doesn't use `#` inside operands).
+func stripComment(line string) string {
+	if idx := strings.IndexByte(line, '#'); idx != -1 {
+		return line[:idx]
+	}
+	return line
+}
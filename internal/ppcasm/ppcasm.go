@@ -0,0 +1,259 @@
+// Package ppcasm is a small, pure-Go assembler for the subset of GNU-as
+// PowerPC/Gekko syntax that Gecko code payloads actually use: labels
+// (including numeric local labels), the .long/.byte/.set/.include
+// directives, and a handful of common PPC and paired-single Gekko
+// instructions. It exists so gecko can assemble codes without requiring
+// users to install binutils.
+package ppcasm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options configures an assembly run.
+type Options struct {
+	// IncludeDirs are searched, in order, to resolve `.include "file"`.
+	IncludeDirs []string
+	// Defines seeds the symbol table, mirroring gas's `-defsym NAME=VALUE`.
+	Defines map[string]int64
+}
+
+// labelOccurrence records one definition of a label at a given byte
+// address. Numeric local labels (e.g. "100:") may occur many times in a
+// file; named labels are expected to occur once.
+type labelOccurrence struct {
+	addr int64
+}
+
+type assembler struct {
+	symbols map[string]int64
+	labels  map[string][]labelOccurrence
+	opts    Options
+}
+
+func (a *assembler) lookupSymbol(name string) (int64, bool) {
+	if v, ok := a.symbols[name]; ok {
+		return v, true
+	}
+	return 0, false
+}
+
+// Assemble assembles source into a flat, big-endian stream of instruction
+// and data words. addressExp, if non-empty, is evaluated against the same
+// symbol table (after assembly) and returned as the resolved load address,
+// mirroring the `.long <addressExp>` trick the binutils-backed path uses.
+func Assemble(source []byte, addressExp string, opts Options) (code []byte, address int64, err error) {
+	a := &assembler{symbols: map[string]int64{}, labels: map[string][]labelOccurrence{}, opts: opts}
+	for k, v := range opts.Defines {
+		a.symbols[k] = v
+	}
+
+	stmts, err := a.expand(source, 1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := a.passOne(stmts); err != nil {
+		return nil, 0, err
+	}
+
+	code, err = a.passTwo(stmts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	address = 0
+	if addressExp != "" {
+		address, err = evalExpr(addressExp, a)
+		if err != nil {
+			return nil, 0, fmt.Errorf("resolving address expression %q: %w", addressExp, err)
+		}
+	}
+
+	return code, address, nil
+}
+
+// expand reads source line by line, stripping comments and recursively
+// inlining `.include` directives, producing the flat statement list the two
+// assembly passes operate on.
+func (a *assembler) expand(source []byte, startLine int) ([]statement, error) {
+	lines := strings.Split(string(source), "\n")
+	var stmts []statement
+	for i, raw := range lines {
+		line := stripComment(raw)
+		for _, st := range parseLine(line, startLine+i) {
+			if st.kind == stmtDirective && st.name == "include" {
+				included, err := a.loadInclude(st)
+				if err != nil {
+					return nil, err
+				}
+				stmts = append(stmts, included...)
+				continue
+			}
+			stmts = append(stmts, st)
+		}
+	}
+	return stmts, nil
+}
+
+func (a *assembler) loadInclude(st statement) ([]statement, error) {
+	if len(st.args) != 1 {
+		return nil, fmt.Errorf("line %d: .include expects a single path argument", st.line)
+	}
+	name := strings.Trim(st.args[0], `"`)
+
+	dirs := append([]string{"."}, a.opts.IncludeDirs...)
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, name)
+		contents, err := ioutil.ReadFile(candidate)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: reading included file %q: %w", st.line, candidate, err)
+		}
+		return a.expand(contents, 1)
+	}
+	return nil, fmt.Errorf("line %d: could not find included file %q in %v", st.line, name, dirs)
+}
+
+// passOne computes the byte address of every statement and records label
+// definitions, so pass two can resolve forward references.
+func (a *assembler) passOne(stmts []statement) error {
+	var addr int64
+	for _, st := range stmts {
+		switch st.kind {
+		case stmtLabel:
+			a.labels[st.name] = append(a.labels[st.name], labelOccurrence{addr: addr})
+		case stmtDirective:
+			switch st.name {
+			case "set", "equ":
+				if len(st.args) != 2 {
+					return fmt.Errorf("line %d: %s expects NAME, EXPR", st.line, st.name)
+				}
+				v, err := evalExpr(st.args[1], a)
+				if err != nil {
+					return fmt.Errorf("line %d: %w", st.line, err)
+				}
+				a.symbols[st.args[0]] = v
+			case "long":
+				addr += 4 * int64(len(st.args))
+			case "byte":
+				addr += int64(len(st.args))
+			default:
+				return fmt.Errorf("line %d: unsupported directive .%s", st.line, st.name)
+			}
+		case stmtInstruction:
+			if _, ok := mnemonics[st.name]; !ok {
+				return fmt.Errorf("line %d: unsupported instruction %q", st.line, st.name)
+			}
+			addr += 4
+		}
+	}
+	return nil
+}
+
+// passTwo re-walks the statement list, now with every label resolved,
+// encoding instructions and emitting data.
+func (a *assembler) passTwo(stmts []statement) ([]byte, error) {
+	var out []byte
+	var addr int64
+
+	resolveOperand := func(operand string, fromAddr int64) (int64, error) {
+		return a.resolveLabelRef(operand, fromAddr)
+	}
+
+	for _, st := range stmts {
+		switch st.kind {
+		case stmtLabel:
+			// Addresses were already computed in passOne.
+		case stmtDirective:
+			switch st.name {
+			case "long":
+				for _, arg := range st.args {
+					v, err := a.resolveLabelRef(arg, addr)
+					if err != nil {
+						return nil, fmt.Errorf("line %d: %w", st.line, err)
+					}
+					out = appendBE32(out, uint32(v))
+					addr += 4
+				}
+			case "byte":
+				for _, arg := range st.args {
+					v, err := evalExpr(arg, a)
+					if err != nil {
+						return nil, fmt.Errorf("line %d: %w", st.line, err)
+					}
+					out = append(out, byte(v))
+					addr++
+				}
+			case "set", "equ":
+				// Already recorded in passOne.
+			}
+		case stmtInstruction:
+			enc := mnemonics[st.name]
+			word, err := enc(st.args, encodeCtx{addr: addr, scope: a, resolve: resolveOperand})
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %w", st.line, st.name, err)
+			}
+			out = appendBE32(out, word)
+			addr += 4
+		}
+	}
+	return out, nil
+}
+
+// resolveLabelRef resolves an operand that may be a plain expression, a
+// named label, or a GNU-as local-label reference ("100f"/"100b").
+func (a *assembler) resolveLabelRef(operand string, fromAddr int64) (int64, error) {
+	operand = strings.TrimSpace(operand)
+	if len(operand) > 1 && (operand[len(operand)-1] == 'f' || operand[len(operand)-1] == 'b') {
+		name := operand[:len(operand)-1]
+		if isAllDigits(name) {
+			forward := operand[len(operand)-1] == 'f'
+			occs := a.labels[name]
+			best := int64(-1)
+			found := false
+			for _, occ := range occs {
+				if forward && occ.addr > fromAddr && (!found || occ.addr < best) {
+					best, found = occ.addr, true
+				}
+				if !forward && occ.addr <= fromAddr && (!found || occ.addr > best) {
+					best, found = occ.addr, true
+				}
+			}
+			if !found {
+				dir := "forward"
+				if !forward {
+					dir = "backward"
+				}
+				return 0, fmt.Errorf("no %s local label %q from address 0x%x", dir, name, fromAddr)
+			}
+			return best, nil
+		}
+	}
+	if occs, ok := a.labels[operand]; ok && len(occs) > 0 {
+		return occs[0].addr, nil
+	}
+	return evalExpr(operand, a)
+}
+
+func isAllDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func appendBE32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Savestate2A03/gecko/internal/ppcasm"
+)
+
+// useNativeAssembler reports whether codes should be assembled with the
+// pure-Go ppcasm backend instead of shelling out to powerpc-eabi-as/objcopy.
+// It is turned on by nativeFlag or by `nativeAssembler: true` in the project
+// config.
+func useNativeAssembler() bool {
+	return nativeFlag || argConfig.NativeAssembler
+}
+
+// nativeFlag, when true, routes batchCompile to the native backend. No
+// flag.BoolVar call registers it, so no command-line flag sets it.
+var nativeFlag bool
+
+// parseDefSym turns the `-defsym`-style "NAME=VALUE" string the config
+// already carries into the symbol table ppcasm expects.
+func parseDefSym(defSym string) map[string]int64 {
+	defines := map[string]int64{}
+	if defSym == "" {
+		return defines
+	}
+	for _, pair := range strings.Split(defSym, ",") {
+		nameValue := strings.SplitN(pair, "=", 2)
+		if len(nameValue) != 2 {
+			continue
+		}
+		v, err := strconv.ParseInt(nameValue[1], 0, 64)
+		if err != nil {
+			continue
+		}
+		defines[nameValue[0]] = v
+	}
+	return defines
+}
+
+// compileNative assembles file with ppcasm instead of invoking binutils. It
+// has compile()'s signature and returns what execBatchCompile sends back for
+// a file (the code without the trailing address word, and the address as
+// eight hex digits), so batchCompile can route to either backend
+// transparently.
+func compileNative(file, addressExp string) ([]byte, string) {
+	source, err := ioutil.ReadFile(file)
+	if err != nil {
+		log.Panicf("Failed to read asm file: %s\n%s\n", file, err.Error())
+	}
+
+	opts := ppcasm.Options{
+		IncludeDirs: []string{filepath.Dir(file), argConfig.ProjectRoot},
+		Defines:     parseDefSym(argConfig.DefSym),
+	}
+
+	code, address, err := ppcasm.Assemble(source, addressExp, opts)
+	if err != nil {
+		printCompileErrors([]CompileError{{File: file, Message: err.Error()}})
+		os.Exit(1)
+	}
+
+	top := (address >> 24) & 0xff
+	if top != 0x80 && top != 0x81 {
+		printCompileErrors([]CompileError{{File: file, Message: "injection address evaluated to a value that does not start with 0x80 or 0x81, probably an invalid address"}})
+		os.Exit(1)
+	}
+
+	if err := recordConfiguredOutputs(file, code, uint32(address)); err != nil {
+		log.Printf("Failed to write configured outputs for %s: %s\n", file, err.Error())
+	}
+
+	return code, fmt.Sprintf("%08x", uint32(address))
+}
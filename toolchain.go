@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// toolchain holds the resolved paths to the assembler and objcopy binaries
+// used to compile and extract Gecko codes.
+type toolchain struct {
+	AsPath      string
+	ObjcopyPath string
+}
+
+// candidateNames returns the binary names we should try for the given tool,
+// in priority order, based on the host platform.
+func candidateNames(tool string) []string {
+	switch tool {
+	case "as":
+		names := []string{"powerpc-eabi-as", "powerpc-linux-gnu-as"}
+		if runtime.GOOS == "windows" {
+			names = append([]string{"powerpc-eabi-as.exe"}, names...)
+		}
+		return names
+	case "objcopy":
+		names := []string{"powerpc-eabi-objcopy", "powerpc-linux-gnu-objcopy"}
+		if runtime.GOOS == "windows" {
+			names = append([]string{"powerpc-eabi-objcopy.exe"}, names...)
+		}
+		return names
+	default:
+		return nil
+	}
+}
+
+// resolveOne finds a usable path for a single tool (either "as" or
+// "objcopy"), checking the explicit override, then the environment
+// variable, then devkitPPC, then $PATH.
+func resolveOne(tool, override, envVar string) (string, error) {
+	if override != "" {
+		if _, err := exec.LookPath(override); err == nil {
+			return override, nil
+		}
+		if _, err := os.Stat(override); err == nil {
+			return override, nil
+		}
+		return "", fmt.Errorf("configured toolchain.%s path %q is not executable", tool, override)
+	}
+
+	if fromEnv := os.Getenv(envVar); fromEnv != "" {
+		if _, err := exec.LookPath(fromEnv); err == nil {
+			return fromEnv, nil
+		}
+		if _, err := os.Stat(fromEnv); err == nil {
+			return fromEnv, nil
+		}
+		return "", fmt.Errorf("%s=%q is not executable", envVar, fromEnv)
+	}
+
+	names := candidateNames(tool)
+
+	if devkitPPC := os.Getenv("DEVKITPPC"); devkitPPC != "" {
+		for _, name := range names {
+			candidate := filepath.Join(devkitPPC, "bin", name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+		}
+	}
+
+	for _, name := range names {
+		if found, err := exec.LookPath(name); err == nil {
+			return found, nil
+		}
+	}
+
+	return "", fmt.Errorf("could not find a %s toolchain binary; tried %v, $%s, and $DEVKITPPC/bin (set toolchain.%s in the config or %s to override)", tool, names, envVar, tool, envVar)
+}
+
+// resolveToolchain locates the assembler and objcopy binaries to use for
+// compilation, consulting (in order of priority) the `toolchain:` block in
+// the config, the GECKO_AS/GECKO_OBJCOPY environment variables, devkitPPC
+// (via $DEVKITPPC), and finally $PATH. It returns a descriptive error
+// instead of letting a later exec.Command call fail with an opaque
+// "executable file not found" error.
+func resolveToolchain() (toolchain, error) {
+	asOverride, objcopyOverride := argConfig.Toolchain.AsPath, argConfig.Toolchain.ObjcopyPath
+
+	asPath, err := resolveOne("as", asOverride, "GECKO_AS")
+	if err != nil {
+		return toolchain{}, err
+	}
+
+	objcopyPath, err := resolveOne("objcopy", objcopyOverride, "GECKO_OBJCOPY")
+	if err != nil {
+		return toolchain{}, err
+	}
+
+	return toolchain{AsPath: asPath, ObjcopyPath: objcopyPath}, nil
+}